@@ -0,0 +1,57 @@
+package manifest
+
+import "fmt"
+
+// Artifact is the packer.Artifact implementation for the manifest
+// post-processor. It never actually represents a build artifact on disk;
+// it's a record of the build that produced one, kept around only so that
+// Packer's core can thread it through as the result of PostProcess.
+type Artifact struct {
+	BuilderType   string            `json:"builder_type"`
+	BuildName     string            `json:"build_name"`
+	BuildTime     int64             `json:"build_time"`
+	ArtifactId    string            `json:"artifact_id"`
+	PackerRunUUID string            `json:"packer_run_uuid"`
+	CustomData    map[string]string `json:"custom_data"`
+	ArtifactFiles []ArtifactFile    `json:"files"`
+}
+
+// ArtifactFile describes a single file produced by the build that the
+// manifest is recording. The digest fields are populated according to
+// Config.Digests and are omitted when digest computation is disabled or
+// skipped for this file by Config.MaxDigestSize.
+type ArtifactFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256,omitempty"`
+	SHA512 string `json:"sha512,omitempty"`
+	Blake3 string `json:"blake3,omitempty"`
+}
+
+func (a *Artifact) BuilderId() string {
+	return "packer.post-processor.manifest"
+}
+
+func (a *Artifact) Files() []string {
+	files := make([]string, 0, len(a.ArtifactFiles))
+	for _, f := range a.ArtifactFiles {
+		files = append(files, f.Name)
+	}
+	return files
+}
+
+func (a *Artifact) Id() string {
+	return a.ArtifactId
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("manifest: %s", a.ArtifactId)
+}
+
+func (a *Artifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	return nil
+}
@@ -0,0 +1,87 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/zeebo/blake3"
+)
+
+const (
+	digestSHA256 = "sha256"
+	digestSHA512 = "sha512"
+	digestBLAKE3 = "blake3"
+)
+
+// defaultDigestAlgorithms is used when Config.Digests is unset.
+var defaultDigestAlgorithms = []string{digestSHA256}
+
+func newHasher(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case digestSHA256:
+		return sha256.New(), nil
+	case digestSHA512:
+		return sha512.New(), nil
+	case digestBLAKE3:
+		return blake3.New(), nil
+	default:
+		return nil, fmt.Errorf("manifest: unknown digest algorithm %q", algorithm)
+	}
+}
+
+// fileDigests streams name through one hasher per requested algorithm via
+// io.MultiWriter, so the file is only read off disk once no matter how
+// many digests are configured. It returns each digest as a lowercase hex
+// string keyed by algorithm name.
+func fileDigests(name string, algorithms []string) (map[string]string, error) {
+	if len(algorithms) == 0 {
+		return nil, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hashers := make(map[string]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, 0, len(algorithms))
+	for _, algorithm := range algorithms {
+		h, err := newHasher(algorithm)
+		if err != nil {
+			return nil, err
+		}
+		hashers[algorithm] = h
+		writers = append(writers, h)
+	}
+
+	if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+		return nil, fmt.Errorf("unable to hash %s: %s", name, err)
+	}
+
+	digests := make(map[string]string, len(hashers))
+	for algorithm, h := range hashers {
+		digests[algorithm] = fmt.Sprintf("%x", h.Sum(nil))
+	}
+	return digests, nil
+}
+
+// recalculateManifestTotals recomputes mf's total_size from the files
+// recorded across all of its builds and sets digest_algorithm to
+// algorithms, the algorithms configured for the current run. It is called
+// on every write since the manifest can span builds from many prior runs.
+func recalculateManifestTotals(mf *ManifestFile, algorithms []string) {
+	mf.DigestAlgorithm = algorithms
+
+	var total int64
+	for _, build := range mf.Builds {
+		for _, file := range build.ArtifactFiles {
+			total += file.Size
+		}
+	}
+	mf.TotalSize = total
+}
@@ -0,0 +1,89 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zeebo/blake3"
+)
+
+func TestFileDigests(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "artifact.bin")
+	content := []byte("packer manifest digest fixture")
+	if err := ioutil.WriteFile(name, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wantSHA256 := fmt.Sprintf("%x", sha256.Sum256(content))
+	wantSHA512 := fmt.Sprintf("%x", sha512.Sum512(content))
+	h := blake3.New()
+	h.Write(content)
+	wantBlake3 := fmt.Sprintf("%x", h.Sum(nil))
+
+	cases := []struct {
+		name       string
+		algorithms []string
+		want       map[string]string
+	}{
+		{
+			name:       "no algorithms returns nil",
+			algorithms: nil,
+			want:       nil,
+		},
+		{
+			name:       "single algorithm",
+			algorithms: []string{digestSHA256},
+			want:       map[string]string{digestSHA256: wantSHA256},
+		},
+		{
+			name:       "multiple algorithms computed from a single read",
+			algorithms: []string{digestSHA256, digestSHA512, digestBLAKE3},
+			want: map[string]string{
+				digestSHA256: wantSHA256,
+				digestSHA512: wantSHA512,
+				digestBLAKE3: wantBlake3,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := fileDigests(name, tc.algorithms)
+			if err != nil {
+				t.Fatalf("fileDigests() error = %s", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("fileDigests() = %v, want %v", got, tc.want)
+			}
+			for algorithm, want := range tc.want {
+				if got[algorithm] != want {
+					t.Errorf("fileDigests()[%s] = %s, want %s", algorithm, got[algorithm], want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileDigestsUnknownAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "artifact.bin")
+	if err := ioutil.WriteFile(name, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fileDigests(name, []string{"md5"}); err == nil {
+		t.Fatal("expected an error for an unsupported digest algorithm")
+	}
+}
+
+func TestFileDigestsMissingFile(t *testing.T) {
+	if _, err := fileDigests(filepath.Join(os.TempDir(), "does-not-exist"), []string{digestSHA256}); err == nil {
+		t.Fatal("expected an error reading a missing file")
+	}
+}
@@ -0,0 +1,102 @@
+package manifest
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// renderManifest marshals mf into the wire format for the given output
+// type. HCL2 and template targets are rendered by their own dedicated code
+// paths and are not handled here.
+func renderManifest(mf *ManifestFile, outType outputType) ([]byte, error) {
+	switch outType {
+	case outputTypeJSON:
+		out, err := json.MarshalIndent(mf, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("manifest: unable to marshal JSON: %s", err)
+		}
+		return out, nil
+	case outputTypeYAML:
+		out, err := yaml.Marshal(mf)
+		if err != nil {
+			return nil, fmt.Errorf("manifest: unable to marshal YAML: %s", err)
+		}
+		return out, nil
+	case outputTypeTOML:
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(mf); err != nil {
+			return nil, fmt.Errorf("manifest: unable to marshal TOML: %s", err)
+		}
+		return buf.Bytes(), nil
+	case outputTypeTar:
+		json, err := renderManifest(mf, outputTypeJSON)
+		if err != nil {
+			return nil, err
+		}
+		return tarWrap("packer-manifest.json", json)
+	default:
+		return nil, fmt.Errorf("manifest: no generic renderer for output type %q", outType)
+	}
+}
+
+// tarWrap packs a single file into an uncompressed tar archive, so the
+// `tar` output type can be piped straight into tooling that expects an OCI
+// layer or a build-context style archive.
+func tarWrap(name string, contents []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	hdr := &tar.Header{
+		Name: name,
+		Mode: 0644,
+		Size: int64(len(contents)),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return nil, fmt.Errorf("manifest: unable to write tar header: %s", err)
+	}
+	if _, err := tw.Write(contents); err != nil {
+		return nil, fmt.Errorf("manifest: unable to write tar entry: %s", err)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("manifest: unable to finalize tar archive: %s", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeTargetBytes writes data to dest, or to stdout when dest is "-",
+// mirroring the BuildKit convention for streaming a single output target.
+func writeTargetBytes(dest string, data []byte) error {
+	if dest == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(dest, data, 0664)
+}
+
+// unmarshalManifest parses previously-written manifest contents of the
+// given structured format back into a ManifestFile, the counterpart to
+// renderManifest for formats other than JSON. It's what lets `type=yaml`
+// and `type=toml` targets round-trip build history the same way
+// `type=json` always has.
+func unmarshalManifest(contents []byte, outType outputType) (*ManifestFile, error) {
+	mf := &ManifestFile{}
+	switch outType {
+	case outputTypeYAML:
+		if err := yaml.Unmarshal(contents, mf); err != nil {
+			return nil, fmt.Errorf("manifest: unable to parse YAML: %s", err)
+		}
+	case outputTypeTOML:
+		if _, err := toml.Decode(string(contents), mf); err != nil {
+			return nil, fmt.Errorf("manifest: unable to parse TOML: %s", err)
+		}
+	default:
+		return nil, fmt.Errorf("manifest: no generic unmarshaler for output type %q", outType)
+	}
+	return mf, nil
+}
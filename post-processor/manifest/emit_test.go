@@ -0,0 +1,128 @@
+package manifest
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderManifestRoundTrip(t *testing.T) {
+	mf := &ManifestFile{
+		SchemaVersion: currentSchemaVersion,
+		LastRunUUID:   "abc",
+		Builds: []Artifact{
+			{ArtifactId: "ami-1", BuildName: "web"},
+		},
+	}
+
+	for _, outType := range []outputType{outputTypeYAML, outputTypeTOML} {
+		t.Run(string(outType), func(t *testing.T) {
+			data, err := renderManifest(mf, outType)
+			if err != nil {
+				t.Fatalf("renderManifest(%s) unexpected error: %s", outType, err)
+			}
+
+			got, err := unmarshalManifest(data, outType)
+			if err != nil {
+				t.Fatalf("unmarshalManifest(%s) unexpected error: %s", outType, err)
+			}
+			if got.LastRunUUID != mf.LastRunUUID {
+				t.Fatalf("unmarshalManifest(%s).LastRunUUID = %q, want %q", outType, got.LastRunUUID, mf.LastRunUUID)
+			}
+			if len(got.Builds) != 1 || got.Builds[0].ArtifactId != "ami-1" {
+				t.Fatalf("unmarshalManifest(%s).Builds = %v, want one build with ArtifactId ami-1", outType, got.Builds)
+			}
+		})
+	}
+}
+
+func TestRenderManifestJSON(t *testing.T) {
+	mf := &ManifestFile{LastRunUUID: "abc"}
+	data, err := renderManifest(mf, outputTypeJSON)
+	if err != nil {
+		t.Fatalf("renderManifest(json) unexpected error: %s", err)
+	}
+	if !bytes.Contains(data, []byte(`"last_run_uuid": "abc"`)) {
+		t.Fatalf("renderManifest(json) = %s, want it to contain last_run_uuid", data)
+	}
+}
+
+func TestRenderManifestTar(t *testing.T) {
+	mf := &ManifestFile{LastRunUUID: "abc"}
+	data, err := renderManifest(mf, outputTypeTar)
+	if err != nil {
+		t.Fatalf("renderManifest(tar) unexpected error: %s", err)
+	}
+
+	tr := tar.NewReader(bytes.NewReader(data))
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatalf("reading tar entry: %s", err)
+	}
+	if hdr.Name != "packer-manifest.json" {
+		t.Fatalf("tar entry name = %q, want %q", hdr.Name, "packer-manifest.json")
+	}
+
+	var contents bytes.Buffer
+	if _, err := contents.ReadFrom(tr); err != nil {
+		t.Fatalf("reading tar entry contents: %s", err)
+	}
+	if !bytes.Contains(contents.Bytes(), []byte(`"last_run_uuid": "abc"`)) {
+		t.Fatalf("tar entry contents = %s, want it to contain last_run_uuid", contents.Bytes())
+	}
+}
+
+func TestRenderManifestUnsupportedType(t *testing.T) {
+	if _, err := renderManifest(&ManifestFile{}, outputTypeHCL2); err == nil {
+		t.Fatal("renderManifest(hcl2) = nil error, want an error (hcl2 has its own dedicated emitter)")
+	}
+}
+
+func TestUnmarshalManifestUnsupportedType(t *testing.T) {
+	if _, err := unmarshalManifest([]byte(`{}`), outputTypeJSON); err == nil {
+		t.Fatal("unmarshalManifest(json) = nil error, want an error (json has its own dedicated reader)")
+	}
+}
+
+func TestWriteTargetBytesFile(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "out.json")
+
+	if err := writeTargetBytes(dest, []byte("hello")); err != nil {
+		t.Fatalf("writeTargetBytes() unexpected error: %s", err)
+	}
+
+	got, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("reading back %s: %s", dest, err)
+	}
+	if string(got) != "hello" {
+		t.Fatalf("writeTargetBytes() wrote %q, want %q", got, "hello")
+	}
+}
+
+func TestWriteTargetBytesStdout(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() unexpected error: %s", err)
+	}
+	origStdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = origStdout }()
+
+	if err := writeTargetBytes("-", []byte("hello")); err != nil {
+		t.Fatalf("writeTargetBytes() unexpected error: %s", err)
+	}
+	w.Close()
+
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading piped stdout: %s", err)
+	}
+	if string(got) != "hello" {
+		t.Fatalf("writeTargetBytes(\"-\", ...) wrote %q to stdout, want %q", got, "hello")
+	}
+}
@@ -0,0 +1,122 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// defaultLockTimeout is used when Config.LockTimeout is unset.
+const defaultLockTimeout = 30 * time.Second
+
+// lockPollInterval is how often a non-blocking lock attempt is retried
+// while waiting for the timeout or ctx to expire.
+const lockPollInterval = 50 * time.Millisecond
+
+// fileLock is a held advisory lock on a `<output>.lock` file. The fd, not
+// the lock file's existence, is what's locked, so a stale lock file left
+// behind by a killed process is reclaimable by the next run.
+type fileLock struct {
+	f *os.File
+}
+
+// acquireLock opens (creating if necessary) path and polls for an
+// exclusive advisory lock until it's obtained, timeout elapses, or ctx is
+// cancelled. This replaces the old O_CREATE|O_EXCL retry loop, which only
+// tested for the lock file's existence and would silently proceed after a
+// few failed attempts, letting concurrent runs clobber each other's
+// manifest.
+func acquireLock(ctx context.Context, path string, timeout time.Duration) (*fileLock, error) {
+	if timeout <= 0 {
+		timeout = defaultLockTimeout
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("manifest: unable to open lock file %s: %s", path, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		lockErr := lockFileHandle(f)
+		if lockErr == nil {
+			return &fileLock{f: f}, nil
+		}
+		if !isLockBusy(lockErr) {
+			f.Close()
+			return nil, fmt.Errorf("manifest: unable to lock %s: %s", path, lockErr)
+		}
+
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, fmt.Errorf("manifest: timed out locking %s: %s", path, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("manifest: timed out locking %s after %s", path, timeout)
+		}
+	}
+}
+
+// Unlock releases the lock and closes the underlying file handle.
+func (l *fileLock) Unlock() {
+	if l == nil || l.f == nil {
+		return
+	}
+	unlockFileHandle(l.f)
+	l.f.Close()
+}
+
+// lockPathsFor derives the full, order-independent set of `<dest>.lock`
+// paths for a set of output targets. It's deduplicated and sorted so that
+// two runs configuring the same destinations in a different order always
+// compute the same lock set, and runs whose destinations only partly
+// overlap still acquire their shared locks in the same order.
+func lockPathsFor(targets []outputTarget) []string {
+	seen := make(map[string]bool, len(targets))
+	var paths []string
+	for _, target := range targets {
+		path := target.Dest + ".lock"
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// manifestLocks holds every lock acquired by acquireLocks, in acquisition
+// order, so they can be released in reverse.
+type manifestLocks []*fileLock
+
+// Unlock releases every held lock, most-recently-acquired first.
+func (locks manifestLocks) Unlock() {
+	for i := len(locks) - 1; i >= 0; i-- {
+		locks[i].Unlock()
+	}
+}
+
+// acquireLocks acquires the lock for every path in paths, in order, so
+// that two runs racing over overlapping destination sets always contend
+// for their shared locks in the same order and can't deadlock each other.
+// If any lock can't be acquired, every lock already held is released
+// before returning the error.
+func acquireLocks(ctx context.Context, paths []string, timeout time.Duration) (manifestLocks, error) {
+	locks := make(manifestLocks, 0, len(paths))
+	for _, path := range paths {
+		lock, err := acquireLock(ctx, path, timeout)
+		if err != nil {
+			locks.Unlock()
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
@@ -0,0 +1,53 @@
+package manifest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLockPathsFor(t *testing.T) {
+	cases := []struct {
+		name    string
+		targets []outputTarget
+		want    []string
+	}{
+		{
+			name:    "single target",
+			targets: []outputTarget{{Dest: "packer-manifest.json"}},
+			want:    []string{"packer-manifest.json.lock"},
+		},
+		{
+			name: "order independent",
+			targets: []outputTarget{
+				{Dest: "out.pkrvars.hcl"},
+				{Dest: "packer-manifest.json"},
+			},
+			want: []string{"out.pkrvars.hcl.lock", "packer-manifest.json.lock"},
+		},
+		{
+			name: "reversed order produces the same lock set",
+			targets: []outputTarget{
+				{Dest: "packer-manifest.json"},
+				{Dest: "out.pkrvars.hcl"},
+			},
+			want: []string{"out.pkrvars.hcl.lock", "packer-manifest.json.lock"},
+		},
+		{
+			name: "duplicate destinations are deduplicated",
+			targets: []outputTarget{
+				{Dest: "packer-manifest.json"},
+				{Dest: "packer-manifest.json"},
+			},
+			want: []string{"packer-manifest.json.lock"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := lockPathsFor(tc.targets)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("lockPathsFor(%v) = %v, want %v", tc.targets, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package manifest
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func lockFileHandle(f *os.File) error {
+	return unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+}
+
+func unlockFileHandle(f *os.File) {
+	unix.Flock(int(f.Fd()), unix.LOCK_UN)
+}
+
+func isLockBusy(err error) bool {
+	return err == unix.EWOULDBLOCK || err == unix.EAGAIN
+}
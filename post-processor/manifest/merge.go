@@ -0,0 +1,39 @@
+package manifest
+
+const (
+	// mergeStrategyAppend is the historical behavior: every build is
+	// added to an ever-growing list.
+	mergeStrategyAppend = "append"
+	// mergeStrategyReplaceByName drops prior builds whose BuildName and
+	// BuilderType match the incoming build before adding it, so the
+	// manifest holds at most one entry per named build.
+	mergeStrategyReplaceByName = "replace-by-name"
+	// mergeStrategyKeepLastN keeps only the most recent Config.KeepLast
+	// builds, oldest first.
+	mergeStrategyKeepLastN = "keep-last-n"
+)
+
+// addBuild applies strategy to record incoming into mf.Builds. This is
+// what lets the manifest double as long-lived state - e.g. a rolling
+// record of the last N Golden AMI builds - instead of an append log that
+// eventually has to be truncated by hand with -force.
+func addBuild(mf *ManifestFile, incoming Artifact, strategy string, keepLast int) {
+	switch strategy {
+	case mergeStrategyReplaceByName:
+		builds := mf.Builds[:0]
+		for _, b := range mf.Builds {
+			if b.BuildName == incoming.BuildName && b.BuilderType == incoming.BuilderType {
+				continue
+			}
+			builds = append(builds, b)
+		}
+		mf.Builds = append(builds, incoming)
+	case mergeStrategyKeepLastN:
+		mf.Builds = append(mf.Builds, incoming)
+		if keepLast > 0 && len(mf.Builds) > keepLast {
+			mf.Builds = mf.Builds[len(mf.Builds)-keepLast:]
+		}
+	default:
+		mf.Builds = append(mf.Builds, incoming)
+	}
+}
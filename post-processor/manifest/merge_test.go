@@ -0,0 +1,80 @@
+package manifest
+
+import "testing"
+
+func TestAddBuild(t *testing.T) {
+	cases := []struct {
+		name     string
+		builds   []Artifact
+		incoming Artifact
+		strategy string
+		keepLast int
+		want     []string // expected resulting build sequence, identified by ArtifactId
+	}{
+		{
+			name:     "append adds to the end",
+			builds:   []Artifact{{BuildName: "a", ArtifactId: "1"}},
+			incoming: Artifact{BuildName: "b", ArtifactId: "2"},
+			strategy: mergeStrategyAppend,
+			want:     []string{"1", "2"},
+		},
+		{
+			name: "replace-by-name drops the matching prior build",
+			builds: []Artifact{
+				{BuildName: "web", BuilderType: "amazon-ebs", ArtifactId: "old"},
+				{BuildName: "db", BuilderType: "amazon-ebs", ArtifactId: "keep"},
+			},
+			incoming: Artifact{BuildName: "web", BuilderType: "amazon-ebs", ArtifactId: "new"},
+			strategy: mergeStrategyReplaceByName,
+			want:     []string{"keep", "new"},
+		},
+		{
+			name: "replace-by-name leaves builds with a different builder type alone",
+			builds: []Artifact{
+				{BuildName: "web", BuilderType: "amazon-ebs", ArtifactId: "ebs"},
+			},
+			incoming: Artifact{BuildName: "web", BuilderType: "docker", ArtifactId: "docker"},
+			strategy: mergeStrategyReplaceByName,
+			want:     []string{"ebs", "docker"},
+		},
+		{
+			name:     "keep-last-n truncates from the front once over the limit",
+			builds:   []Artifact{{ArtifactId: "1"}, {ArtifactId: "2"}, {ArtifactId: "3"}},
+			incoming: Artifact{ArtifactId: "4"},
+			strategy: mergeStrategyKeepLastN,
+			keepLast: 3,
+			want:     []string{"2", "3", "4"},
+		},
+		{
+			name:     "keep-last-n is a no-op while under the limit",
+			builds:   []Artifact{{ArtifactId: "1"}},
+			incoming: Artifact{ArtifactId: "2"},
+			strategy: mergeStrategyKeepLastN,
+			keepLast: 3,
+			want:     []string{"1", "2"},
+		},
+		{
+			name:     "unknown strategy falls back to append",
+			builds:   []Artifact{{ArtifactId: "1"}},
+			incoming: Artifact{ArtifactId: "2"},
+			strategy: "bogus",
+			want:     []string{"1", "2"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mf := &ManifestFile{Builds: append([]Artifact(nil), tc.builds...)}
+			addBuild(mf, tc.incoming, tc.strategy, tc.keepLast)
+
+			if len(mf.Builds) != len(tc.want) {
+				t.Fatalf("addBuild() left %d builds, want %d (%v)", len(mf.Builds), len(tc.want), mf.Builds)
+			}
+			for i, b := range mf.Builds {
+				if b.ArtifactId != tc.want[i] {
+					t.Fatalf("addBuild() build[%d].ArtifactId = %q, want %q", i, b.ArtifactId, tc.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// currentSchemaVersion is written to every manifest on emit. Bump it, and
+// add a case to migrate, whenever ManifestFile's on-disk shape changes in
+// a way that plain json.Unmarshal would handle wrong (a rename, a type
+// change, a field that needs to be derived rather than defaulted).
+const currentSchemaVersion = 2
+
+// migrate parses oldBytes, the previously-written manifest contents, into
+// the current ManifestFile shape. Before this existed, an on-disk
+// manifest from an older schema was read with plain json.Unmarshal, which
+// silently drops unknown fields instead of erroring or migrating them.
+func migrate(oldBytes []byte) (*ManifestFile, error) {
+	mf := &ManifestFile{}
+	if len(oldBytes) == 0 {
+		mf.SchemaVersion = currentSchemaVersion
+		return mf, nil
+	}
+
+	var probe struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(oldBytes, &probe); err != nil {
+		return nil, fmt.Errorf("manifest: unable to determine schema_version: %s", err)
+	}
+
+	switch probe.SchemaVersion {
+	case 0:
+		// Pre-versioning manifests already have the same Builds/
+		// LastRunUUID shape as schema 2 - total_size and digest_algorithm
+		// were added additively - so a direct unmarshal is the migration.
+		if err := json.Unmarshal(oldBytes, mf); err != nil {
+			return nil, fmt.Errorf("manifest: unable to parse schema 0 manifest: %s", err)
+		}
+	case currentSchemaVersion:
+		if err := json.Unmarshal(oldBytes, mf); err != nil {
+			return nil, fmt.Errorf("manifest: unable to parse manifest: %s", err)
+		}
+	default:
+		return nil, fmt.Errorf("manifest: unsupported schema_version %d", probe.SchemaVersion)
+	}
+
+	mf.SchemaVersion = currentSchemaVersion
+	return mf, nil
+}
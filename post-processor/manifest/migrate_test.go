@@ -0,0 +1,62 @@
+package manifest
+
+import "testing"
+
+func TestMigrate(t *testing.T) {
+	t.Run("empty input starts a fresh manifest at the current schema", func(t *testing.T) {
+		mf, err := migrate(nil)
+		if err != nil {
+			t.Fatalf("migrate(nil) unexpected error: %s", err)
+		}
+		if mf.SchemaVersion != currentSchemaVersion {
+			t.Fatalf("migrate(nil).SchemaVersion = %d, want %d", mf.SchemaVersion, currentSchemaVersion)
+		}
+		if len(mf.Builds) != 0 {
+			t.Fatalf("migrate(nil).Builds = %v, want empty", mf.Builds)
+		}
+	})
+
+	t.Run("schema 0 manifests migrate in place", func(t *testing.T) {
+		oldBytes := []byte(`{"builds":[{"build_name":"web","artifact_id":"1"}],"last_run_uuid":"abc"}`)
+		mf, err := migrate(oldBytes)
+		if err != nil {
+			t.Fatalf("migrate(schema 0) unexpected error: %s", err)
+		}
+		if mf.SchemaVersion != currentSchemaVersion {
+			t.Fatalf("migrate(schema 0).SchemaVersion = %d, want %d", mf.SchemaVersion, currentSchemaVersion)
+		}
+		if len(mf.Builds) != 1 || mf.Builds[0].ArtifactId != "1" {
+			t.Fatalf("migrate(schema 0).Builds = %v, want one build with ArtifactId 1", mf.Builds)
+		}
+		if mf.LastRunUUID != "abc" {
+			t.Fatalf("migrate(schema 0).LastRunUUID = %q, want %q", mf.LastRunUUID, "abc")
+		}
+	})
+
+	t.Run("current schema round-trips", func(t *testing.T) {
+		oldBytes := []byte(`{"schema_version":2,"builds":[],"total_size":42,"digest_algorithm":["sha256"]}`)
+		mf, err := migrate(oldBytes)
+		if err != nil {
+			t.Fatalf("migrate(current schema) unexpected error: %s", err)
+		}
+		if mf.TotalSize != 42 {
+			t.Fatalf("migrate(current schema).TotalSize = %d, want 42", mf.TotalSize)
+		}
+		if len(mf.DigestAlgorithm) != 1 || mf.DigestAlgorithm[0] != "sha256" {
+			t.Fatalf("migrate(current schema).DigestAlgorithm = %v, want [sha256]", mf.DigestAlgorithm)
+		}
+	})
+
+	t.Run("future schema versions are rejected", func(t *testing.T) {
+		oldBytes := []byte(`{"schema_version":99}`)
+		if _, err := migrate(oldBytes); err == nil {
+			t.Fatal("migrate(schema 99) = nil error, want an error")
+		}
+	})
+
+	t.Run("malformed input is rejected", func(t *testing.T) {
+		if _, err := migrate([]byte("not json")); err == nil {
+			t.Fatal("migrate(invalid json) = nil error, want an error")
+		}
+	})
+}
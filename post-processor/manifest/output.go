@@ -0,0 +1,121 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// outputType identifies which emitter renders a configured output target.
+type outputType string
+
+const (
+	outputTypeJSON     outputType = "json"
+	outputTypeHCL2     outputType = "hcl2"
+	outputTypeYAML     outputType = "yaml"
+	outputTypeTOML     outputType = "toml"
+	outputTypeTar      outputType = "tar"
+	outputTypeTemplate outputType = "template"
+)
+
+// outputTarget is a single destination parsed out of the `output` field,
+// following the same grammar as BuildKit's `--output type=…,dest=…` flag.
+type outputTarget struct {
+	Type outputType
+	Dest string
+	// Src is the path to a user-supplied template file and is only
+	// meaningful for outputTypeTemplate.
+	Src string
+}
+
+// defaultOutputType infers a target's emitter from its destination's file
+// extension. This preserves the historical behavior from before multiple
+// outputs were supported, where `output` was always a bare path.
+func defaultOutputType(dest string) outputType {
+	if strings.HasSuffix(dest, ".pkrvars.hcl") {
+		return outputTypeHCL2
+	}
+	return outputTypeJSON
+}
+
+// parseOutputSpecs parses the `output` field into one or more output
+// targets. Multiple targets are separated by `;`; each target is either a
+// bare destination path (the historical single-output behavior) or a
+// comma-separated list of `key=value` pairs such as
+// `type=json,dest=packer-manifest.json`.
+func parseOutputSpecs(raw string) ([]outputTarget, error) {
+	if raw == "" {
+		raw = "packer-manifest.json"
+	}
+
+	var targets []outputTarget
+	for _, chunk := range strings.Split(raw, ";") {
+		chunk = strings.TrimSpace(chunk)
+		if chunk == "" {
+			continue
+		}
+		target, err := parseOutputSpec(chunk)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+func parseOutputSpec(raw string) (outputTarget, error) {
+	fields := strings.Split(raw, ",")
+
+	// A single bare value with no `key=value` pairs is shorthand for
+	// `dest=<value>`, matching the pre-existing single-path behavior.
+	if len(fields) == 1 && !strings.Contains(fields[0], "=") {
+		dest := fields[0]
+		return outputTarget{Type: defaultOutputType(dest), Dest: dest}, nil
+	}
+
+	var target outputTarget
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return outputTarget{}, fmt.Errorf("manifest: invalid output field %q, expected key=value", field)
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		switch key {
+		case "type":
+			target.Type = outputType(value)
+		case "dest":
+			target.Dest = value
+		case "src":
+			target.Src = value
+		default:
+			return outputTarget{}, fmt.Errorf("manifest: unknown output field %q", key)
+		}
+	}
+
+	// A lone `dest=<value>` field needs no type=; as with a bare path, the
+	// type is inferred from the destination.
+	if target.Type == "" && len(fields) == 1 {
+		target.Type = defaultOutputType(target.Dest)
+	}
+
+	if target.Type == "" {
+		return outputTarget{}, fmt.Errorf("manifest: output %q must set type= when more than one field is present", raw)
+	}
+	if target.Dest == "" {
+		return outputTarget{}, fmt.Errorf("manifest: output %q is missing dest=", raw)
+	}
+
+	switch target.Type {
+	case outputTypeJSON, outputTypeHCL2, outputTypeYAML, outputTypeTOML, outputTypeTar, outputTypeTemplate:
+	default:
+		return outputTarget{}, fmt.Errorf("manifest: unknown output type %q", target.Type)
+	}
+	if target.Type == outputTypeTemplate && target.Src == "" {
+		return outputTarget{}, fmt.Errorf("manifest: output %q uses type=template but is missing src=", raw)
+	}
+
+	return target, nil
+}
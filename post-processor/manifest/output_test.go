@@ -0,0 +1,102 @@
+package manifest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOutputSpecs(t *testing.T) {
+	cases := []struct {
+		name    string
+		raw     string
+		want    []outputTarget
+		wantErr bool
+	}{
+		{
+			name: "empty defaults to packer-manifest.json",
+			raw:  "",
+			want: []outputTarget{{Type: outputTypeJSON, Dest: "packer-manifest.json"}},
+		},
+		{
+			name: "bare path infers json",
+			raw:  "packer-manifest.json",
+			want: []outputTarget{{Type: outputTypeJSON, Dest: "packer-manifest.json"}},
+		},
+		{
+			name: "bare path with .pkrvars.hcl suffix infers hcl2",
+			raw:  "out.pkrvars.hcl",
+			want: []outputTarget{{Type: outputTypeHCL2, Dest: "out.pkrvars.hcl"}},
+		},
+		{
+			name: "single key=value target",
+			raw:  "type=yaml,dest=packer-manifest.yaml",
+			want: []outputTarget{{Type: outputTypeYAML, Dest: "packer-manifest.yaml"}},
+		},
+		{
+			name: "multiple targets separated by semicolons",
+			raw:  "type=json,dest=packer-manifest.json;type=hcl2,dest=out.pkrvars.hcl",
+			want: []outputTarget{
+				{Type: outputTypeJSON, Dest: "packer-manifest.json"},
+				{Type: outputTypeHCL2, Dest: "out.pkrvars.hcl"},
+			},
+		},
+		{
+			name: "template target with src",
+			raw:  "type=template,dest=inventory.ini,src=./tpl.gotmpl",
+			want: []outputTarget{{Type: outputTypeTemplate, Dest: "inventory.ini", Src: "./tpl.gotmpl"}},
+		},
+		{
+			name: "dest=- writes to stdout",
+			raw:  "type=json,dest=-",
+			want: []outputTarget{{Type: outputTypeJSON, Dest: "-"}},
+		},
+		{
+			name:    "more than one field without type= is ambiguous",
+			raw:     "dest=packer-manifest.json,src=whatever",
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			raw:     "type=xml,dest=out.xml",
+			wantErr: true,
+		},
+		{
+			name:    "missing dest",
+			raw:     "type=json",
+			wantErr: true,
+		},
+		{
+			name:    "template without src",
+			raw:     "type=template,dest=out.txt",
+			wantErr: true,
+		},
+		{
+			name:    "malformed key=value field",
+			raw:     "type=json,dest",
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			raw:     "type=json,dest=out.json,color=blue",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseOutputSpecs(tc.raw)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseOutputSpecs(%q) = %v, want an error", tc.raw, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseOutputSpecs(%q) unexpected error: %s", tc.raw, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("parseOutputSpecs(%q) = %#v, want %#v", tc.raw, got, tc.want)
+			}
+		})
+	}
+}
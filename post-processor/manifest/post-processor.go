@@ -6,13 +6,10 @@ package manifest
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
@@ -30,7 +27,15 @@ type Config struct {
 
 	// The manifest will be written to this file. This defaults to
 	// `packer-manifest.json`. When using a file that ends with ".pkrvars.hcl"
-	// a hclvars version of the manifest will be generated.
+	// a hclvars version of the manifest will be generated. This field also
+	// accepts a BuildKit-style `--output` grammar for writing more than one
+	// target from a single invocation, e.g.
+	// `type=json,dest=packer-manifest.json;type=hcl2,dest=out.pkrvars.hcl`.
+	// Targets are separated by `;`; within a target, fields are
+	// comma-separated `key=value` pairs. `type=` is required once a target
+	// has more than one field. Supported types are `json`, `hcl2`, `yaml`,
+	// `toml`, `tar`, and `template` (see the `format` option). `dest=-`
+	// writes to stdout.
 	OutputPath string `mapstructure:"output"`
 	// Write only filename without the path to the manifest file. This defaults
 	// to false.
@@ -41,7 +46,88 @@ type Config struct {
 	// engine](https://packer.io/docs/templates/engine.html). Therefore, you
 	// may use user variables and template functions in this field.
 	CustomData map[string]string `mapstructure:"custom_data"`
-	ctx        interpolate.Context
+	// A Go [text/template](https://golang.org/pkg/text/template/) string
+	// (with [sprig](http://masterminds.github.io/sprig/) functions
+	// available) used to render the manifest in place of its default JSON
+	// encoding, in the spirit of `docker system df --format`. The template
+	// receives `.SchemaVersion`, `.Builds`, `.LastRunUUID`, `.TotalSize`,
+	// and `.DigestAlgorithm`; each build exposes `ArtifactId`, `BuildName`,
+	// `BuilderType`, `CustomData`, and `Files` (each with `Name`, `Size`,
+	// and any configured digests). This is how CSV, Markdown tables,
+	// tfvars, or Ansible inventories get written without a second tool.
+	Format string `mapstructure:"format"`
+	// Digest algorithms to compute for each artifact file, turning the
+	// manifest into a verifiable bill-of-materials that signing/attestation
+	// tooling like cosign or in-toto can consume. Defaults to `["sha256"]`.
+	// Set to an empty list to disable digest computation. Supported values
+	// are `sha256`, `sha512`, and `blake3`.
+	Digests []string `mapstructure:"digests"`
+	// Skip digest computation for any artifact file larger than this many
+	// bytes. 0 (the default) means no limit.
+	MaxDigestSize int64 `mapstructure:"max_digest_size"`
+	// How long to wait to acquire the manifest lock before giving up.
+	// Accepts a duration string such as "30s" or "5m". Defaults to 30s.
+	LockTimeout string `mapstructure:"lock_timeout"`
+	// Skip locking the output file entirely. Useful in CI environments
+	// where each run already writes to its own unique output path.
+	NoLock bool `mapstructure:"no_lock"`
+	// When set, the rendered manifest is also pushed as an OCI artifact to
+	// a registry after the local output targets are written.
+	Registry *RegistryConfig `mapstructure:"registry"`
+	// How incoming builds are merged into the manifest's existing Builds
+	// list: `append` (the default) keeps every build ever recorded,
+	// `replace-by-name` drops prior builds that share the incoming
+	// build's `BuildName`/`BuilderType`, and `keep-last-n` truncates to
+	// the most recent `KeepLast` builds. Anything other than `append`
+	// needs a `json`, `yaml`, or `toml` output target to read prior
+	// builds back from; `hcl2` merges by build name through its own
+	// path and never consults this field.
+	MergeStrategy string `mapstructure:"merge_strategy"`
+	// With `merge_strategy = "keep-last-n"`, the number of most recent
+	// builds to retain.
+	KeepLast int `mapstructure:"keep_last"`
+	ctx      interpolate.Context
+
+	lockTimeout time.Duration
+
+	// outputs is the parsed form of OutputPath, computed once in Configure.
+	outputs []outputTarget
+}
+
+// RegistryConfig publishes the manifest to an OCI registry as a
+// single-blob artifact, giving downstream Kubernetes/Argo/Flux pipelines
+// one addressable, immutable pointer to what a build produced.
+type RegistryConfig struct {
+	// OCI repository to push to, e.g. "ghcr.io/org/image-manifests".
+	Repository string `mapstructure:"repository"`
+	// Tag to push under. Defaults to "latest".
+	Tag string `mapstructure:"tag"`
+	// Optional "user:pass" basic auth. When unset, credentials are
+	// resolved the way Docker and go-containerregistry's
+	// `authn.DefaultKeychain` do, including `~/.docker/config.json` and
+	// cloud provider credential helpers.
+	Auth string `mapstructure:"auth"`
+	// Allow pushing to a registry over plain HTTP.
+	Insecure bool `mapstructure:"insecure"`
+	// Media type of the manifest layer. Defaults to "application/json".
+	MediaType string `mapstructure:"media_type"`
+}
+
+// hasReadableOutput reports whether at least one configured output target
+// can be read back into a ManifestFile on a later run. json/yaml/toml all
+// round-trip through loadManifestFile. hcl2 does not count even though it
+// also reads its destination back: Hcl2Manifest merges by build name into
+// its own nested-map structure rather than ManifestFile.Builds, so it
+// never consults MergeStrategy/KeepLast - a stateful merge_strategy needs
+// one of json/yaml/toml configured. tar and template are write-only.
+func (c *Config) hasReadableOutput() bool {
+	for _, target := range c.outputs {
+		switch target.Type {
+		case outputTypeJSON, outputTypeYAML, outputTypeTOML:
+			return true
+		}
+	}
+	return false
 }
 
 type PostProcessor struct {
@@ -49,8 +135,16 @@ type PostProcessor struct {
 }
 
 type ManifestFile struct {
-	Builds      []Artifact `json:"builds"`
-	LastRunUUID string     `json:"last_run_uuid"`
+	// SchemaVersion is written on every emit and read back by migrate to
+	// decide how to interpret an existing manifest on disk.
+	SchemaVersion int        `json:"schema_version"`
+	Builds        []Artifact `json:"builds"`
+	LastRunUUID   string     `json:"last_run_uuid"`
+	// TotalSize and DigestAlgorithm are recomputed on every write:
+	// TotalSize sums the files recorded across all builds in Builds, and
+	// DigestAlgorithm lists the digest algorithms configured for this run.
+	TotalSize       int64    `json:"total_size,omitempty"`
+	DigestAlgorithm []string `json:"digest_algorithm,omitempty"`
 }
 
 func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
@@ -75,6 +169,33 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return fmt.Errorf("Error parsing target template: %s", err)
 	}
 
+	if p.config.outputs, err = parseOutputSpecs(p.config.OutputPath); err != nil {
+		return err
+	}
+
+	p.config.lockTimeout = defaultLockTimeout
+	if p.config.LockTimeout != "" {
+		if p.config.lockTimeout, err = time.ParseDuration(p.config.LockTimeout); err != nil {
+			return fmt.Errorf("Error parsing lock_timeout: %s", err)
+		}
+	}
+
+	if p.config.MergeStrategy == "" {
+		p.config.MergeStrategy = mergeStrategyAppend
+	}
+	switch p.config.MergeStrategy {
+	case mergeStrategyAppend, mergeStrategyReplaceByName, mergeStrategyKeepLastN:
+	default:
+		return fmt.Errorf("Unknown merge_strategy %q", p.config.MergeStrategy)
+	}
+	if p.config.MergeStrategy != mergeStrategyAppend && !p.config.hasReadableOutput() {
+		return fmt.Errorf("merge_strategy %q needs a json, yaml, or toml output target to read prior builds back from; "+
+			"hcl2 merges by build name through its own path and doesn't apply merge_strategy, and tar/template targets are write-only", p.config.MergeStrategy)
+	}
+	if p.config.MergeStrategy == mergeStrategyKeepLastN && p.config.KeepLast <= 0 {
+		return fmt.Errorf("merge_strategy %q requires keep_last to be set to a positive number of builds to retain", mergeStrategyKeepLastN)
+	}
+
 	return nil
 }
 
@@ -100,6 +221,11 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packer.Ui, source pa
 	var fi os.FileInfo
 	var aFiles []ArtifactFile
 
+	digestAlgorithms := p.config.Digests
+	if digestAlgorithms == nil {
+		digestAlgorithms = defaultDigestAlgorithms
+	}
+
 	// Create the current artifact.
 	for _, name := range source.Files() {
 		af := ArtifactFile{}
@@ -111,22 +237,22 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packer.Ui, source pa
 		} else {
 			af.Name = name
 		}
-		aFiles = append(aFiles, af)
-	}
-
-	// Create a lock file with exclusive access. If this fails we will retry
-	// after a delay.
-	lockFilename := p.config.OutputPath + ".lock"
-	defer lockFile(lockFilename)()
 
-	// Read the current manifest file from disk
-	var contents []byte
-	if contents, err = ioutil.ReadFile(p.config.OutputPath); err != nil && !os.IsNotExist(err) {
-		return source, true, true, fmt.Errorf("Unable to open %s for reading: %s", p.config.OutputPath, err)
-	}
+		fileDigestAlgorithms := digestAlgorithms
+		if p.config.MaxDigestSize > 0 && af.Size > p.config.MaxDigestSize {
+			fileDigestAlgorithms = nil
+		}
+		if len(fileDigestAlgorithms) > 0 {
+			digests, err := fileDigests(name, fileDigestAlgorithms)
+			if err != nil {
+				return source, true, true, fmt.Errorf("Unable to compute digests for %s: %s", name, err)
+			}
+			af.SHA256 = digests[digestSHA256]
+			af.SHA512 = digests[digestSHA512]
+			af.Blake3 = digests[digestBLAKE3]
+		}
 
-	if strings.HasSuffix(p.config.OutputPath, ".pkrvars.hcl") {
-		return p.Hcl2Manifest(ctx, ui, contents, source, aFiles)
+		aFiles = append(aFiles, af)
 	}
 
 	artifact.ArtifactFiles = aFiles
@@ -147,12 +273,22 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packer.Ui, source pa
 	// the file before we proceed.
 	artifact.PackerRunUUID = os.Getenv("PACKER_RUN_UUID")
 
-	// Parse the manifest file JSON, if we have one
-	manifestFile := &ManifestFile{}
-	if len(contents) > 0 {
-		if err = json.Unmarshal(contents, manifestFile); err != nil {
-			return source, true, true, fmt.Errorf("Unable to parse content from %s: %s", p.config.OutputPath, err)
+	// Every configured target is locked, not just the first, so that two
+	// Packer runs sharing any output path are serialized even when they
+	// declare `output` in a different order, or one configures a subset
+	// of the other's targets. The read-modify-write below happens
+	// entirely while the locks are held.
+	if !p.config.NoLock {
+		locks, err := acquireLocks(ctx, lockPathsFor(p.config.outputs), p.config.lockTimeout)
+		if err != nil {
+			return source, true, true, err
 		}
+		defer locks.Unlock()
+	}
+
+	manifestFile, err := p.loadManifestFile()
+	if err != nil {
+		return source, true, true, err
 	}
 
 	// If -force is set and we are not on same run, truncate the file. Otherwise
@@ -162,16 +298,21 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packer.Ui, source pa
 	}
 
 	// Add the current artifact to the manifest file
-	manifestFile.Builds = append(manifestFile.Builds, *artifact)
+	addBuild(manifestFile, *artifact, p.config.MergeStrategy, p.config.KeepLast)
 	manifestFile.LastRunUUID = os.Getenv("PACKER_RUN_UUID")
+	recalculateManifestTotals(manifestFile, digestAlgorithms)
+	manifestFile.SchemaVersion = currentSchemaVersion
+
+	for _, target := range p.config.outputs {
+		if err := p.writeTarget(ctx, ui, target, manifestFile, source, aFiles); err != nil {
+			return source, true, true, err
+		}
+	}
 
-	// Write JSON to disk
-	if out, err := json.MarshalIndent(manifestFile, "", "  "); err == nil {
-		if err = ioutil.WriteFile(p.config.OutputPath, out, 0664); err != nil {
-			return source, true, true, fmt.Errorf("Unable to write %s: %s", p.config.OutputPath, err)
+	if p.config.Registry != nil {
+		if err := p.pushRegistry(ctx, manifestFile); err != nil {
+			return source, true, true, err
 		}
-	} else {
-		return source, true, true, fmt.Errorf("Unable to marshal JSON %s", err)
 	}
 
 	// The manifest should never delete the artifacts it is set to record, so it
@@ -179,29 +320,109 @@ func (p *PostProcessor) PostProcess(ctx context.Context, ui packer.Ui, source pa
 	return source, true, true, nil
 }
 
-// Create a lock file with exclusive access. If this fails we will retry
-// after a delay.
-func lockFile(lockFilename string) (cleanup func()) {
-	for i := 0; i < 3; i++ {
-		// The file should not be locked for very long so we'll keep this short.
-		time.Sleep((time.Duration(i) * 200 * time.Millisecond))
-		_, err := os.OpenFile(lockFilename, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
-		if err == nil {
-			break
+// pushRegistry renders manifestFile into whichever format Config.Registry.
+// MediaType declares and publishes it as an OCI artifact, independent of
+// the local output targets.
+func (p *PostProcessor) pushRegistry(ctx context.Context, manifestFile *ManifestFile) error {
+	data, mediaType, err := renderRegistryPayload(manifestFile, p.config.Registry)
+	if err != nil {
+		return err
+	}
+
+	annotations := map[string]string{
+		"io.packer.build_name":   p.config.PackerBuildName,
+		"io.packer.builder_type": p.config.PackerBuilderType,
+		"io.packer.run_uuid":     os.Getenv("PACKER_RUN_UUID"),
+	}
+	for k, v := range p.config.CustomData {
+		annotations["io.packer.custom_data."+k] = v
+	}
+
+	return pushManifestArtifact(ctx, p.config.Registry, data, mediaType, annotations)
+}
+
+// loadManifestFile reconstructs the manifest's prior state so a new build
+// can be appended to it. JSON, YAML, and TOML targets all round-trip,
+// since all three marshal the same ManifestFile; a `json` target is
+// preferred when more than one is configured, since it's the only format
+// that carries pre-schema-versioning history through migrate. If none of
+// those are configured (e.g. only `tar`, `hcl2`, or `template`), each run
+// starts fresh - Configure rejects that combination outright for any
+// merge_strategy that depends on reading prior state back.
+func (p *PostProcessor) loadManifestFile() (*ManifestFile, error) {
+	for _, format := range []outputType{outputTypeJSON, outputTypeYAML, outputTypeTOML} {
+		for _, target := range p.config.outputs {
+			if target.Type != format {
+				continue
+			}
+
+			contents, err := ioutil.ReadFile(target.Dest)
+			if err != nil {
+				if os.IsNotExist(err) {
+					return migrate(nil)
+				}
+				return nil, fmt.Errorf("Unable to open %s for reading: %s", target.Dest, err)
+			}
+
+			if format == outputTypeJSON {
+				manifestFile, err := migrate(contents)
+				if err != nil {
+					return nil, fmt.Errorf("Unable to parse content from %s: %s", target.Dest, err)
+				}
+				return manifestFile, nil
+			}
+
+			manifestFile, err := unmarshalManifest(contents, format)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to parse content from %s: %s", target.Dest, err)
+			}
+			manifestFile.SchemaVersion = currentSchemaVersion
+			return manifestFile, nil
+		}
+	}
+
+	return migrate(nil)
+}
+
+// writeTarget renders manifestFile through the emitter for target.Type and
+// writes the result to target.Dest.
+func (p *PostProcessor) writeTarget(ctx context.Context, ui packer.Ui, target outputTarget, manifestFile *ManifestFile, source packer.Artifact, aFiles []ArtifactFile) error {
+	switch target.Type {
+	case outputTypeHCL2:
+		contents, err := ioutil.ReadFile(target.Dest)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Unable to open %s for reading: %s", target.Dest, err)
 		}
-		log.Printf("Error locking manifest file for reading and writing. Will sleep and retry. %s", err)
+		_, _, _, err = p.Hcl2Manifest(ctx, ui, contents, target.Dest, source, aFiles)
+		return err
+	case outputTypeTemplate:
+		return p.writeTemplate(target, manifestFile)
+	default:
+		var out []byte
+		var err error
+		if target.Type == outputTypeJSON && p.config.Format != "" {
+			out, err = renderManifestTemplate(manifestFile, p.config.Format)
+		} else {
+			out, err = renderManifest(manifestFile, target.Type)
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeTargetBytes(target.Dest, out); err != nil {
+			return fmt.Errorf("Unable to write %s: %s", target.Dest, err)
+		}
+		return nil
 	}
-	return func() { os.Remove(lockFilename) }
 }
 
-func (p *PostProcessor) Hcl2Manifest(ctx context.Context, ui packer.Ui, contents []byte, source packer.Artifact, aFiles []ArtifactFile) (packer.Artifact, bool, bool, error) {
+func (p *PostProcessor) Hcl2Manifest(ctx context.Context, ui packer.Ui, contents []byte, dest string, source packer.Artifact, aFiles []ArtifactFile) (packer.Artifact, bool, bool, error) {
 	buildName := p.config.PackerBuildGroupName
 	sourceName := p.config.PackerBuildName
 	sourceType := p.config.PackerBuilderType
 
-	file, diags := hclparse.NewParser().ParseHCL(contents, p.config.OutputPath)
+	file, diags := hclparse.NewParser().ParseHCL(contents, dest)
 	if diags.HasErrors() {
-		err := fmt.Errorf("Failed to parse output file %s: %s", p.config.OutputPath, diags)
+		err := fmt.Errorf("Failed to parse output file %s: %s", dest, diags)
 		return source, true, true, err
 	}
 
@@ -230,8 +451,11 @@ func (p *PostProcessor) Hcl2Manifest(ctx context.Context, ui packer.Ui, contents
 		files := []interface{}{}
 		for _, v := range aFiles {
 			files = append(files, map[string]interface{}{
-				"name": v.Name,
-				"size": v.Size,
+				"name":   v.Name,
+				"size":   v.Size,
+				"sha256": v.SHA256,
+				"sha512": v.SHA512,
+				"blake3": v.Blake3,
 			})
 		}
 		entry["files"] = files
@@ -254,7 +478,25 @@ func (p *PostProcessor) Hcl2Manifest(ctx context.Context, ui packer.Ui, contents
 		return source, true, true, err
 	}
 
-	return source, true, true, ioutil.WriteFile(p.config.OutputPath, out.Bytes(), 0664)
+	return source, true, true, writeTargetBytes(dest, out.Bytes())
+}
+
+// writeTemplate renders manifestFile through the user-supplied template
+// file at target.Src, for `type=template,dest=…,src=…` output targets.
+func (p *PostProcessor) writeTemplate(target outputTarget, manifestFile *ManifestFile) error {
+	tplBytes, err := ioutil.ReadFile(target.Src)
+	if err != nil {
+		return fmt.Errorf("manifest: unable to read template %s: %s", target.Src, err)
+	}
+
+	out, err := renderManifestTemplate(manifestFile, string(tplBytes))
+	if err != nil {
+		return err
+	}
+	if err := writeTargetBytes(target.Dest, out); err != nil {
+		return fmt.Errorf("Unable to write %s: %s", target.Dest, err)
+	}
+	return nil
 }
 
 func setMapVal(target, toSet map[string]interface{}, keys ...string) {
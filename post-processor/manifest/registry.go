@@ -0,0 +1,114 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// manifestArtifactConfigMediaType identifies the OCI artifact's config
+// blob, which is the image config go-containerregistry derives from
+// empty.Image and the single appended layer.
+const manifestArtifactConfigMediaType = "application/vnd.packer.manifest.v1+json"
+
+// registryMediaTypes maps a RegistryConfig.MediaType value to the emitter
+// that actually produces bytes in that format, so the uploaded layer is
+// never mislabeled. An empty MediaType defaults to JSON.
+var registryMediaTypes = map[string]outputType{
+	"":                   outputTypeJSON,
+	"application/json":   outputTypeJSON,
+	"application/yaml":   outputTypeYAML,
+	"application/x-yaml": outputTypeYAML,
+	"application/toml":   outputTypeTOML,
+}
+
+// renderRegistryPayload renders mf into the bytes that will actually be
+// pushed, resolving cfg.MediaType to the emitter that produces them. It
+// returns the effective media type (falling back to "application/json"
+// when cfg.MediaType was unset) so the two never drift apart.
+func renderRegistryPayload(mf *ManifestFile, cfg *RegistryConfig) (data []byte, mediaType string, err error) {
+	outType, ok := registryMediaTypes[cfg.MediaType]
+	if !ok {
+		return nil, "", fmt.Errorf("manifest: registry.media_type %q has no matching renderer; use application/json, application/yaml, or application/toml", cfg.MediaType)
+	}
+
+	data, err = renderManifest(mf, outType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	mediaType = cfg.MediaType
+	if mediaType == "" {
+		mediaType = "application/json"
+	}
+	return data, mediaType, nil
+}
+
+// pushManifestArtifact uploads data as a single-layer OCI 1.1 artifact
+// under mediaType, annotated with build metadata, so downstream
+// Kubernetes/Argo/Flux pipelines have one addressable, immutable pointer
+// to "what did Packer just build" without needing a shared filesystem.
+func pushManifestArtifact(ctx context.Context, cfg *RegistryConfig, data []byte, mediaType string, annotations map[string]string) error {
+	tag := cfg.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+
+	var nameOpts []name.Option
+	if cfg.Insecure {
+		nameOpts = append(nameOpts, name.Insecure)
+	}
+	ref, err := name.ParseReference(fmt.Sprintf("%s:%s", cfg.Repository, tag), nameOpts...)
+	if err != nil {
+		return fmt.Errorf("manifest: invalid registry.repository %q: %s", cfg.Repository, err)
+	}
+
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer: static.NewLayer(data, types.MediaType(mediaType)),
+	})
+	if err != nil {
+		return fmt.Errorf("manifest: unable to build OCI artifact: %s", err)
+	}
+	img = mutate.MediaType(img, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, manifestArtifactConfigMediaType)
+	img, ok := mutate.Annotations(img, annotations).(v1.Image)
+	if !ok {
+		return fmt.Errorf("manifest: unable to annotate OCI artifact")
+	}
+
+	authOpt, err := registryAuthOption(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := remote.Write(ref, img, remote.WithContext(ctx), authOpt); err != nil {
+		return fmt.Errorf("manifest: unable to push manifest artifact to %s: %s", ref, err)
+	}
+	return nil
+}
+
+// registryAuthOption resolves push credentials. An explicit `auth` field
+// of "user:pass" wins; otherwise credentials are resolved the way
+// go-containerregistry's authn.DefaultKeychain does, which covers
+// ~/.docker/config.json and the cloud provider credential helpers.
+func registryAuthOption(cfg *RegistryConfig) (remote.Option, error) {
+	if cfg.Auth == "" {
+		return remote.WithAuthFromKeychain(authn.DefaultKeychain), nil
+	}
+
+	for i := 0; i < len(cfg.Auth); i++ {
+		if cfg.Auth[i] == ':' {
+			user, pass := cfg.Auth[:i], cfg.Auth[i+1:]
+			return remote.WithAuth(&authn.Basic{Username: user, Password: pass}), nil
+		}
+	}
+	return nil, fmt.Errorf("manifest: registry.auth must be in \"user:pass\" form")
+}
@@ -0,0 +1,46 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderRegistryPayload(t *testing.T) {
+	mf := &ManifestFile{SchemaVersion: currentSchemaVersion, LastRunUUID: "abc"}
+
+	cases := []struct {
+		name      string
+		mediaType string
+		wantType  string
+		wantErr   bool
+		contains  string
+	}{
+		{name: "empty defaults to json", mediaType: "", wantType: "application/json", contains: "abc"},
+		{name: "explicit json", mediaType: "application/json", wantType: "application/json", contains: "abc"},
+		{name: "yaml", mediaType: "application/yaml", wantType: "application/yaml", contains: "abc"},
+		{name: "x-yaml alias", mediaType: "application/x-yaml", wantType: "application/x-yaml", contains: "abc"},
+		{name: "toml", mediaType: "application/toml", wantType: "application/toml", contains: "abc"},
+		{name: "unsupported media type", mediaType: "application/xml", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, mediaType, err := renderRegistryPayload(mf, &RegistryConfig{MediaType: tc.mediaType})
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("renderRegistryPayload(media_type=%q) = %s, want an error", tc.mediaType, data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderRegistryPayload(media_type=%q) unexpected error: %s", tc.mediaType, err)
+			}
+			if mediaType != tc.wantType {
+				t.Fatalf("renderRegistryPayload(media_type=%q) mediaType = %q, want %q", tc.mediaType, mediaType, tc.wantType)
+			}
+			if !strings.Contains(string(data), tc.contains) {
+				t.Fatalf("renderRegistryPayload(media_type=%q) data = %s, want it to contain %q", tc.mediaType, data, tc.contains)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// templateManifest and templateBuild are the shapes exposed to `format`
+// and `type=template` output rendering. They mirror ManifestFile and
+// Artifact but with template-friendly field names (e.g. Files, rather
+// than the "files" json tag) so format strings read naturally. Each
+// Files entry is an ArtifactFile, so SHA256/SHA512/Blake3 are available
+// alongside Name/Size wherever digests are configured.
+type templateManifest struct {
+	SchemaVersion   int
+	Builds          []templateBuild
+	LastRunUUID     string
+	TotalSize       int64
+	DigestAlgorithm []string
+}
+
+type templateBuild struct {
+	ArtifactId  string
+	BuildName   string
+	BuilderType string
+	CustomData  map[string]string
+	Files       []ArtifactFile
+}
+
+func newTemplateManifest(mf *ManifestFile) templateManifest {
+	tm := templateManifest{
+		SchemaVersion:   mf.SchemaVersion,
+		LastRunUUID:     mf.LastRunUUID,
+		TotalSize:       mf.TotalSize,
+		DigestAlgorithm: mf.DigestAlgorithm,
+	}
+	for _, b := range mf.Builds {
+		tm.Builds = append(tm.Builds, templateBuild{
+			ArtifactId:  b.ArtifactId,
+			BuildName:   b.BuildName,
+			BuilderType: b.BuilderType,
+			CustomData:  b.CustomData,
+			Files:       b.ArtifactFiles,
+		})
+	}
+	return tm
+}
+
+// renderManifestTemplate renders mf through a Go text/template, with the
+// sprig function library available for string and list manipulation. This
+// is how the `format` option and `type=template` output targets emit
+// arbitrary text formats (CSV, Markdown tables, tfvars, inventories, CI
+// matrix JSON, ...) from a single post-processor invocation.
+func renderManifestTemplate(mf *ManifestFile, tplText string) ([]byte, error) {
+	tpl, err := template.New("manifest").Funcs(sprig.TxtFuncMap()).Parse(tplText)
+	if err != nil {
+		return nil, fmt.Errorf("manifest: unable to parse format template: %s", err)
+	}
+
+	var out bytes.Buffer
+	if err := tpl.Execute(&out, newTemplateManifest(mf)); err != nil {
+		return nil, fmt.Errorf("manifest: unable to render format template: %s", err)
+	}
+	return out.Bytes(), nil
+}
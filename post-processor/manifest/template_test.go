@@ -0,0 +1,101 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewTemplateManifest(t *testing.T) {
+	mf := &ManifestFile{
+		SchemaVersion:   currentSchemaVersion,
+		LastRunUUID:     "abc",
+		TotalSize:       42,
+		DigestAlgorithm: []string{"sha256"},
+		Builds: []Artifact{
+			{
+				ArtifactId:  "ami-1",
+				BuildName:   "web",
+				BuilderType: "amazon-ebs",
+				CustomData:  map[string]string{"env": "prod"},
+				ArtifactFiles: []ArtifactFile{
+					{Name: "disk.raw", Size: 1024, SHA256: "deadbeef"},
+				},
+			},
+		},
+	}
+
+	tm := newTemplateManifest(mf)
+
+	if tm.SchemaVersion != currentSchemaVersion || tm.LastRunUUID != "abc" || tm.TotalSize != 42 {
+		t.Fatalf("newTemplateManifest() top-level fields = %+v, want schema/uuid/size carried over", tm)
+	}
+	if len(tm.DigestAlgorithm) != 1 || tm.DigestAlgorithm[0] != "sha256" {
+		t.Fatalf("newTemplateManifest().DigestAlgorithm = %v, want [sha256]", tm.DigestAlgorithm)
+	}
+	if len(tm.Builds) != 1 {
+		t.Fatalf("newTemplateManifest().Builds = %v, want one build", tm.Builds)
+	}
+
+	b := tm.Builds[0]
+	if b.ArtifactId != "ami-1" || b.BuildName != "web" || b.BuilderType != "amazon-ebs" {
+		t.Fatalf("newTemplateManifest().Builds[0] = %+v, want fields copied from Artifact", b)
+	}
+	if b.CustomData["env"] != "prod" {
+		t.Fatalf("newTemplateManifest().Builds[0].CustomData = %v, want env=prod", b.CustomData)
+	}
+	if len(b.Files) != 1 || b.Files[0].SHA256 != "deadbeef" {
+		t.Fatalf("newTemplateManifest().Builds[0].Files = %v, want the ArtifactFiles carried over with digests", b.Files)
+	}
+}
+
+func TestRenderManifestTemplateCSV(t *testing.T) {
+	mf := &ManifestFile{
+		Builds: []Artifact{
+			{ArtifactId: "ami-1", BuildName: "web", BuilderType: "amazon-ebs"},
+			{ArtifactId: "ami-2", BuildName: "db", BuilderType: "amazon-ebs"},
+		},
+	}
+
+	out, err := renderManifestTemplate(mf, `{{range .Builds}}{{.BuildName}},{{.ArtifactId}}
+{{end}}`)
+	if err != nil {
+		t.Fatalf("renderManifestTemplate() unexpected error: %s", err)
+	}
+
+	want := "web,ami-1\ndb,ami-2\n"
+	if string(out) != want {
+		t.Fatalf("renderManifestTemplate() = %q, want %q", out, want)
+	}
+}
+
+func TestRenderManifestTemplateSprigFunc(t *testing.T) {
+	mf := &ManifestFile{LastRunUUID: "abc"}
+
+	out, err := renderManifestTemplate(mf, `{{upper .LastRunUUID}}`)
+	if err != nil {
+		t.Fatalf("renderManifestTemplate() unexpected error: %s", err)
+	}
+	if string(out) != "ABC" {
+		t.Fatalf("renderManifestTemplate() = %q, want %q (sprig's upper func)", out, "ABC")
+	}
+}
+
+func TestRenderManifestTemplateParseError(t *testing.T) {
+	_, err := renderManifestTemplate(&ManifestFile{}, `{{.Builds`)
+	if err == nil {
+		t.Fatal("renderManifestTemplate() with an unclosed action = nil error, want an error")
+	}
+	if !strings.Contains(err.Error(), "parse") {
+		t.Fatalf("renderManifestTemplate() error = %q, want it to mention template parsing", err)
+	}
+}
+
+func TestRenderManifestTemplateExecError(t *testing.T) {
+	_, err := renderManifestTemplate(&ManifestFile{}, `{{.NoSuchField}}`)
+	if err == nil {
+		t.Fatal("renderManifestTemplate() referencing an unknown field = nil error, want an error")
+	}
+	if !strings.Contains(err.Error(), "render") {
+		t.Fatalf("renderManifestTemplate() error = %q, want it to mention template rendering", err)
+	}
+}